@@ -0,0 +1,219 @@
+// Package binarylog captures the raw header/message/trailer traffic of a
+// stream to a Sink, modelled after gRPC's binary logging facility. It is
+// meant to be enabled selectively, on a "service/method" glob, to debug
+// a single misbehaving route without paying for full packet capture.
+package binarylog
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// LoggerType identifies which side of the call produced an Entry.
+type LoggerType string
+
+const (
+	LoggerClient LoggerType = "client"
+	LoggerServer LoggerType = "server"
+)
+
+// EntryType identifies what a logged Entry represents.
+type EntryType string
+
+const (
+	TypeClientHeader    EntryType = "ClientHeader"
+	TypeServerHeader    EntryType = "ServerHeader"
+	TypeClientMessage   EntryType = "ClientMessage"
+	TypeServerMessage   EntryType = "ServerMessage"
+	TypeClientHalfClose EntryType = "ClientHalfClose"
+	TypeServerTrailer   EntryType = "ServerTrailer"
+	TypeCancel          EntryType = "Cancel"
+)
+
+// Entry is a single logged event. It intentionally mirrors the shape of
+// MethodLogger entries in gRPC's binary log: one Entry per header frame,
+// message, half-close or cancellation.
+type Entry struct {
+	Timestamp time.Time
+	CallID    string
+	StreamID  string
+	Logger    LoggerType
+	Type      EntryType
+	Peer      string
+
+	Headers  map[string]string
+	Trailers map[string]string
+
+	// Payload is the raw message bytes, truncated to the configured
+	// message length limit. MessageLengthTruncated reports whether that
+	// truncation happened.
+	Payload                []byte
+	MessageLengthTruncated bool
+}
+
+// Sink is the destination binary log Entries are written to.
+type Sink interface {
+	Write(entry *Entry) error
+	Close() error
+}
+
+// MethodLogger captures the Entries for a single stream and forwards
+// them to a Sink. One is constructed per stream (via NewMethodLogger);
+// NewEncoderFilter/NewDecoderFilter feed it the header/data/trailer
+// frames from the filter chain, and Attach registers it on the Stream
+// so it sees resets.
+type MethodLogger struct {
+	sink          Sink
+	side          LoggerType
+	callID        string
+	streamID      string
+	headerMaxLen  uint32
+	messageMaxLen uint32
+}
+
+// LogEntry truncates the entry's Headers/Payload to the configured
+// limits and writes it to the sink.
+func (l *MethodLogger) LogEntry(e *Entry) {
+	if l == nil || l.sink == nil {
+		return
+	}
+
+	e.CallID = l.callID
+	e.StreamID = l.streamID
+	e.Timestamp = time.Now()
+
+	if l.messageMaxLen > 0 && uint32(len(e.Payload)) > l.messageMaxLen {
+		// e.Payload is a slice of the caller's live buffer; re-slicing it
+		// in place would be fine (it doesn't mutate the backing array),
+		// but copy it anyway so truncation behaves the same way it does
+		// for Headers below and a future Sink can't observe past
+		// messageMaxLen by growing the slice back.
+		truncated := make([]byte, l.messageMaxLen)
+		copy(truncated, e.Payload)
+		e.Payload = truncated
+		e.MessageLengthTruncated = true
+	}
+
+	if l.headerMaxLen > 0 && e.Headers != nil {
+		// e.Headers is the same map the decode/encode filter chain keeps
+		// using for routing and forwarding after this call returns, so
+		// truncating values in place would corrupt the real request --
+		// log a copy instead.
+		headers := make(map[string]string, len(e.Headers))
+		for k, v := range e.Headers {
+			if uint32(len(v)) > l.headerMaxLen {
+				v = v[:l.headerMaxLen]
+			}
+			headers[k] = v
+		}
+		e.Headers = headers
+	}
+
+	l.sink.Write(e)
+}
+
+// OnResetStream implements types.StreamEventListener, so a MethodLogger
+// can be registered directly on the real Stream via AddEventListener --
+// the only place a reset reason is actually reachable from.
+// StreamEncoderFilter/StreamDecoderFilter only embed StreamFilterBase,
+// which exposes nothing but OnDestroy, so the filter chain itself never
+// sees a reset; callers must pass the MethodLogger to Attach alongside
+// NewEncoderFilter/NewDecoderFilter.
+//
+// A reset whose reason is StreamRemoteReset is always logged as a
+// Cancel, even if the server-supplied status text looks like
+// "canceled" -- the distinction that matters here is who originated the
+// reset, not what the payload said, since the server may have
+// voluntarily returned Canceled while the client actually just dropped
+// the connection. The entry's Logger is l.side, the side this
+// MethodLogger was constructed for, not always the server.
+func (l *MethodLogger) OnResetStream(reason types.StreamResetReason) {
+	if reason != types.StreamRemoteReset {
+		return
+	}
+	l.LogEntry(&Entry{
+		Logger: l.side,
+		Type:   TypeCancel,
+	})
+}
+
+func (l *MethodLogger) OnAboveWriteBufferHighWatermark() {}
+
+func (l *MethodLogger) OnBelowWriteBufferLowWatermark() {}
+
+// Config is the live, admin-tunable configuration for the binary log
+// subsystem: which calls get logged, and how much of each is kept.
+type Config struct {
+	// Patterns is a list of "service/method" glob patterns. A call is
+	// logged if any pattern matches "service/method", "service/*" or
+	// "*".
+	Patterns []string
+
+	HeaderMaxLen  uint32
+	MessageMaxLen uint32
+}
+
+var (
+	mu  sync.RWMutex
+	cfg Config
+	sk  Sink
+)
+
+// UpdateConfig replaces the live configuration and sink. It is intended
+// to be called from the admin API handler that exposes binary log
+// pattern configuration over HTTP, so operators can toggle capture on
+// live traffic without a restart.
+func UpdateConfig(newConfig Config, sink Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = newConfig
+	sk = sink
+}
+
+// GetConfig returns the live pattern configuration, e.g. for the admin
+// API to report it back to an operator.
+func GetConfig() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return cfg
+}
+
+// NewMethodLogger returns a MethodLogger for the given service/method if
+// the live configuration has a pattern matching it, and false otherwise.
+// side identifies which end of the call the owning StreamConnection is
+// -- the client that sent the request or the server that received it --
+// and is reported as every logged Entry's Logger field.
+func NewMethodLogger(service, method, callID, streamID string, side LoggerType) (*MethodLogger, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if sk == nil || !matches(cfg.Patterns, service, method) {
+		return nil, false
+	}
+
+	return &MethodLogger{
+		sink:          sk,
+		side:          side,
+		callID:        callID,
+		streamID:      streamID,
+		headerMaxLen:  cfg.HeaderMaxLen,
+		messageMaxLen: cfg.MessageMaxLen,
+	}, true
+}
+
+func matches(patterns []string, service, method string) bool {
+	full := service + "/" + method
+	for _, p := range patterns {
+		switch {
+		case p == "*":
+			return true
+		case p == full:
+			return true
+		case p == service+"/*":
+			return true
+		}
+	}
+	return false
+}
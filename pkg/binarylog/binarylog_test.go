@@ -0,0 +1,68 @@
+package binarylog
+
+import (
+	"testing"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+type recordingSink struct {
+	entries []*Entry
+}
+
+func (s *recordingSink) Write(e *Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestLogEntryTruncatesCopyNotOriginal(t *testing.T) {
+	sink := &recordingSink{}
+	logger := &MethodLogger{sink: sink, headerMaxLen: 4, messageMaxLen: 4}
+
+	original := map[string]string{"authorization": "Bearer supersecrettoken"}
+	payload := []byte("supersecrettoken")
+
+	logger.LogEntry(&Entry{Headers: original, Payload: payload})
+
+	if got := original["authorization"]; got != "Bearer supersecrettoken" {
+		t.Fatalf("LogEntry mutated the caller's header map: got %q", got)
+	}
+	if string(payload) != "supersecrettoken" {
+		t.Fatalf("LogEntry mutated the caller's payload slice: got %q", payload)
+	}
+
+	logged := sink.entries[0]
+	if got := logged.Headers["authorization"]; got != "Bear" {
+		t.Fatalf("logged entry not truncated: got %q", got)
+	}
+	if string(logged.Payload) != "supe" {
+		t.Fatalf("logged payload not truncated: got %q", logged.Payload)
+	}
+}
+
+func TestOnResetStreamUsesConfiguredSide(t *testing.T) {
+	sink := &recordingSink{}
+	logger := &MethodLogger{sink: sink, side: LoggerClient}
+
+	logger.OnResetStream(types.StreamRemoteReset)
+
+	if len(sink.entries) != 1 {
+		t.Fatalf("expected one Cancel entry, got %d", len(sink.entries))
+	}
+	if sink.entries[0].Logger != LoggerClient {
+		t.Fatalf("expected Cancel entry logged as %q, got %q", LoggerClient, sink.entries[0].Logger)
+	}
+}
+
+func TestOnResetStreamIgnoresLocalReset(t *testing.T) {
+	sink := &recordingSink{}
+	logger := &MethodLogger{sink: sink, side: LoggerServer}
+
+	logger.OnResetStream(types.StreamLocalReset)
+
+	if len(sink.entries) != 0 {
+		t.Fatalf("expected no entry for a local reset, got %d", len(sink.entries))
+	}
+}
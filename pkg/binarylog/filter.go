@@ -0,0 +1,114 @@
+package binarylog
+
+import (
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// Attach registers logger's Cancel-entry hook directly on stream.
+// Callers must call it alongside NewEncoderFilter/NewDecoderFilter when
+// constructing the StreamEncoder/StreamDecoder pair for a stream: the
+// header/data/trailer path is reachable from the filter chain, but a
+// reset is only ever delivered to a types.StreamEventListener added
+// directly to the Stream.
+func Attach(stream types.Stream, logger *MethodLogger) {
+	stream.AddEventListener(logger)
+}
+
+// encoderFilter and decoderFilter wire MethodLogger into the existing
+// StreamEncoderFilter/StreamDecoderFilter chain, so enabling binary
+// logging for a route doesn't require touching the protocol-specific
+// StreamConnection implementations for the header/data/trailer path --
+// only ResetStream, which isn't reachable from a filter, needs Attach.
+type encoderFilter struct {
+	logger     *MethodLogger
+	loggerSide LoggerType
+	cb         types.StreamEncoderFilterCallbacks
+}
+
+// NewEncoderFilter wraps logger as a StreamEncoderFilter. side indicates
+// whether the frames flowing through this encoder are a client request
+// or a server response.
+func NewEncoderFilter(logger *MethodLogger, side LoggerType) types.StreamEncoderFilter {
+	return &encoderFilter{logger: logger, loggerSide: side}
+}
+
+func (f *encoderFilter) OnDestroy() {}
+
+func (f *encoderFilter) SetEncoderFilterCallbacks(cb types.StreamEncoderFilterCallbacks) {
+	f.cb = cb
+}
+
+func (f *encoderFilter) EncodeHeaders(headers interface{}, endStream bool) types.FilterHeadersStatus {
+	headerType := TypeClientHeader
+	if f.loggerSide == LoggerServer {
+		headerType = TypeServerHeader
+	}
+	if h, ok := headers.(map[string]string); ok {
+		f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: headerType, Headers: h})
+	}
+	return types.FilterHeadersStatusContinue
+}
+
+func (f *encoderFilter) EncodeData(buf types.IoBuffer, endStream bool) types.FilterDataStatus {
+	messageType := TypeClientMessage
+	if f.loggerSide == LoggerServer {
+		messageType = TypeServerMessage
+	}
+	f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: messageType, Payload: buf.Bytes()})
+	if endStream && f.loggerSide == LoggerClient {
+		f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: TypeClientHalfClose})
+	}
+	return types.FilterDataStatusContinue
+}
+
+func (f *encoderFilter) EncodeTrailers(trailers map[string]string) types.FilterTrailersStatus {
+	if f.loggerSide == LoggerServer {
+		f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: TypeServerTrailer, Trailers: trailers})
+	}
+	return types.FilterTrailersStatusContinue
+}
+
+type decoderFilter struct {
+	logger     *MethodLogger
+	loggerSide LoggerType
+	cb         types.StreamDecoderFilterCallbacks
+}
+
+// NewDecoderFilter wraps logger as a StreamDecoderFilter.
+func NewDecoderFilter(logger *MethodLogger, side LoggerType) types.StreamDecoderFilter {
+	return &decoderFilter{logger: logger, loggerSide: side}
+}
+
+func (f *decoderFilter) OnDestroy() {}
+
+func (f *decoderFilter) SetDecoderFilterCallbacks(cb types.StreamDecoderFilterCallbacks) {
+	f.cb = cb
+}
+
+func (f *decoderFilter) DecodeHeaders(headers map[string]string, endStream bool) types.FilterHeadersStatus {
+	headerType := TypeClientHeader
+	if f.loggerSide == LoggerServer {
+		headerType = TypeServerHeader
+	}
+	f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: headerType, Headers: headers})
+	return types.FilterHeadersStatusContinue
+}
+
+func (f *decoderFilter) DecodeData(buf types.IoBuffer, endStream bool) types.FilterDataStatus {
+	messageType := TypeClientMessage
+	if f.loggerSide == LoggerServer {
+		messageType = TypeServerMessage
+	}
+	f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: messageType, Payload: buf.Bytes()})
+	if endStream && f.loggerSide == LoggerClient {
+		f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: TypeClientHalfClose})
+	}
+	return types.FilterDataStatusContinue
+}
+
+func (f *decoderFilter) DecodeTrailers(trailers map[string]string) types.FilterTrailersStatus {
+	if f.loggerSide == LoggerServer {
+		f.logger.LogEntry(&Entry{Logger: f.loggerSide, Type: TypeServerTrailer, Trailers: trailers})
+	}
+	return types.FilterTrailersStatusContinue
+}
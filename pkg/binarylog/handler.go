@@ -0,0 +1,50 @@
+package binarylog
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns the HTTP handler MOSN's admin server mounts to expose
+// and update the binary log pattern configuration live:
+//
+//	GET  /binarylog/config - the current Config
+//	POST /binarylog/config - replace Patterns/HeaderMaxLen/MessageMaxLen
+//	                         with the JSON body, so operators can turn
+//	                         capture on for a misbehaving route without a
+//	                         restart
+//
+// POST never changes the configured Sink -- that's wired up once at
+// startup, not something an operator toggles per request.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/binarylog/config", handleConfig)
+	return mux
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, GetConfig())
+	case http.MethodPost:
+		var newConfig Config
+		if err := json.NewDecoder(r.Body).Decode(&newConfig); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.RLock()
+		sink := sk
+		mu.RUnlock()
+
+		UpdateConfig(newConfig, sink)
+		writeJSON(w, newConfig)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,240 @@
+// Package channelz provides gRPC-channelz-style runtime introspection
+// for MOSN's dataplane: every types.Listener and the types.Connection /
+// types.StreamConnection / types.Stream traffic flowing through it are
+// registered in an in-memory tree addressed by monotonically increasing
+// IDs, so operators can inspect live mesh state without standing up a
+// tracing pipeline.
+package channelz
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var nextID int64
+
+func newID() int64 {
+	return atomic.AddInt64(&nextID, 1)
+}
+
+var (
+	mu      sync.RWMutex
+	servers = map[int64]*Server{}
+	sockets = map[int64]*Socket{}
+)
+
+// Server is the channelz node for a types.Listener.
+type Server struct {
+	ID   int64
+	Name string
+
+	mu      sync.Mutex
+	sockets map[int64]*Socket
+}
+
+// RegisterServer registers a new Listener under the given name and
+// returns its channelz node.
+func RegisterServer(name string) *Server {
+	s := &Server{
+		ID:      newID(),
+		Name:    name,
+		sockets: map[int64]*Socket{},
+	}
+
+	mu.Lock()
+	servers[s.ID] = s
+	mu.Unlock()
+
+	return s
+}
+
+// RemoveServer unregisters a Listener, e.g. on Close.
+func RemoveServer(id int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(servers, id)
+}
+
+// RegisterSocket registers a new accepted Connection as a child of s and
+// returns its channelz node. fd is the raw socket descriptor, used to
+// pull getsockopt(TCP_INFO) stats on platforms that support it; pass -1
+// if unavailable.
+func (s *Server) RegisterSocket(remoteAddr string, fd int) *Socket {
+	sock := &Socket{
+		ID:         newID(),
+		RemoteAddr: remoteAddr,
+		fd:         fd,
+		streams:    map[int64]*Stream{},
+	}
+
+	s.mu.Lock()
+	s.sockets[sock.ID] = sock
+	s.mu.Unlock()
+
+	mu.Lock()
+	sockets[sock.ID] = sock
+	mu.Unlock()
+
+	return sock
+}
+
+// RemoveSocket unregisters a Connection, e.g. on close.
+func (s *Server) RemoveSocket(id int64) {
+	s.mu.Lock()
+	delete(s.sockets, id)
+	s.mu.Unlock()
+
+	mu.Lock()
+	delete(sockets, id)
+	mu.Unlock()
+}
+
+// Sockets returns a snapshot of the sockets currently registered under s.
+func (s *Server) Sockets() []*Socket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*Socket, 0, len(s.sockets))
+	for _, sock := range s.sockets {
+		out = append(out, sock)
+	}
+	return out
+}
+
+// GetServer looks up a registered Listener node by ID.
+func GetServer(id int64) (*Server, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := servers[id]
+	return s, ok
+}
+
+// GetSocket looks up a registered Connection node by ID.
+func GetSocket(id int64) (*Socket, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := sockets[id]
+	return s, ok
+}
+
+// Servers returns a snapshot of every registered Listener node.
+func Servers() []*Server {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := make([]*Server, 0, len(servers))
+	for _, s := range servers {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Stream is the channelz node for a single active types.Stream. It gets
+// its own ID but no top-level registry entry: its counters roll up
+// into the parent Socket, which is what the /channelz/socket/{id}
+// endpoint reports.
+type Stream struct {
+	ID int64
+}
+
+// Socket is the channelz node for a types.Connection carrying a
+// types.StreamConnection.
+type Socket struct {
+	ID         int64
+	RemoteAddr string
+
+	fd int
+
+	mu      sync.Mutex
+	streams map[int64]*Stream
+
+	StreamsStarted          int64
+	StreamsSucceeded        int64
+	StreamsFailed           int64
+	MessagesSent            int64
+	MessagesReceived        int64
+	BytesSent               int64
+	BytesReceived           int64
+	KeepAlivesSent          int64
+	LastCallStartedUnixNano int64
+
+	resetMu      sync.Mutex
+	resetReasons map[string]int64
+}
+
+// NewStream records a stream starting on this socket and returns a
+// channelz Stream handle for it.
+func (s *Socket) NewStream() *Stream {
+	atomic.AddInt64(&s.StreamsStarted, 1)
+	atomic.StoreInt64(&s.LastCallStartedUnixNano, time.Now().UnixNano())
+
+	st := &Stream{ID: newID()}
+	s.mu.Lock()
+	s.streams[st.ID] = st
+	s.mu.Unlock()
+	return st
+}
+
+// EndStream removes the stream from the socket and records it as
+// succeeded or failed.
+func (s *Socket) EndStream(st *Stream, succeeded bool) {
+	s.mu.Lock()
+	delete(s.streams, st.ID)
+	s.mu.Unlock()
+
+	if succeeded {
+		atomic.AddInt64(&s.StreamsSucceeded, 1)
+	} else {
+		atomic.AddInt64(&s.StreamsFailed, 1)
+	}
+}
+
+// ResetStream records a stream reset, keyed by reason, for the reset
+// reasons histogram.
+func (s *Socket) ResetStream(reason string) {
+	s.resetMu.Lock()
+	if s.resetReasons == nil {
+		s.resetReasons = map[string]int64{}
+	}
+	s.resetReasons[reason]++
+	s.resetMu.Unlock()
+
+	atomic.AddInt64(&s.StreamsFailed, 1)
+}
+
+// ResetReasons returns a snapshot of the reset reasons histogram.
+func (s *Socket) ResetReasons() map[string]int64 {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+
+	out := make(map[string]int64, len(s.resetReasons))
+	for k, v := range s.resetReasons {
+		out[k] = v
+	}
+	return out
+}
+
+func (s *Socket) MessageSent(bytes int) {
+	atomic.AddInt64(&s.MessagesSent, 1)
+	atomic.AddInt64(&s.BytesSent, int64(bytes))
+}
+
+func (s *Socket) MessageReceived(bytes int) {
+	atomic.AddInt64(&s.MessagesReceived, 1)
+	atomic.AddInt64(&s.BytesReceived, int64(bytes))
+}
+
+func (s *Socket) KeepAliveSent() {
+	atomic.AddInt64(&s.KeepAlivesSent, 1)
+}
+
+// Info returns the socket's current getsockopt(TCP_INFO) snapshot
+// (RTT, congestion window, retransmits). On platforms without Linux's
+// TCP_INFO support it is always the zero value.
+func (s *Socket) Info() SocketInfo {
+	if s.fd < 0 {
+		return SocketInfo{}
+	}
+	return getSocketInfo(s.fd)
+}
@@ -0,0 +1,74 @@
+package channelz
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler returns the HTTP handler MOSN's admin server mounts to expose
+// channelz state:
+//
+//	GET /channelz/servers       - every registered listener
+//	GET /channelz/server/{id}   - one listener and its sockets
+//	GET /channelz/socket/{id}   - one socket's counters
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/channelz/servers", handleServers)
+	mux.HandleFunc("/channelz/server/", handleServer)
+	mux.HandleFunc("/channelz/socket/", handleSocket)
+	return mux
+}
+
+func handleServers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, Servers())
+}
+
+func handleServer(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path, "/channelz/server/")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, ok := GetServer(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, struct {
+		*Server
+		Sockets []*Socket `json:"sockets"`
+	}{s, s.Sockets()})
+}
+
+func handleSocket(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r.URL.Path, "/channelz/socket/")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, ok := GetSocket(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, struct {
+		*Socket
+		ResetReasons map[string]int64 `json:"resetReasons"`
+		Info         SocketInfo       `json:"info"`
+	}{s, s.ResetReasons(), s.Info()})
+}
+
+func idFromPath(path, prefix string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(path, prefix), 10, 64)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,41 @@
+//go:build linux
+// +build linux
+
+package channelz
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// SocketInfo is the subset of Linux's getsockopt(TCP_INFO) channelz
+// surfaces to operators.
+type SocketInfo struct {
+	RTTMicros   uint32
+	Cwnd        uint32
+	Retransmits uint32
+}
+
+func getSocketInfo(fd int) SocketInfo {
+	var info syscall.TCPInfo
+	size := uint32(unsafe.Sizeof(info))
+
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_GETSOCKOPT,
+		uintptr(fd),
+		uintptr(syscall.SOL_TCP),
+		uintptr(syscall.TCP_INFO),
+		uintptr(unsafe.Pointer(&info)),
+		uintptr(unsafe.Pointer(&size)),
+		0,
+	)
+	if errno != 0 {
+		return SocketInfo{}
+	}
+
+	return SocketInfo{
+		RTTMicros:   info.Rtt,
+		Cwnd:        info.Snd_cwnd,
+		Retransmits: uint32(info.Retransmits),
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package channelz
+
+// SocketInfo is the subset of Linux's getsockopt(TCP_INFO) channelz
+// surfaces to operators. It is always the zero value on platforms
+// without TCP_INFO support.
+type SocketInfo struct {
+	RTTMicros   uint32
+	Cwnd        uint32
+	Retransmits uint32
+}
+
+func getSocketInfo(fd int) SocketInfo {
+	return SocketInfo{}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"gitlab.alipay-inc.com/afe/mosn/pkg/api/v2"
+	"gitlab.alipay-inc.com/afe/mosn/pkg/channelz"
 	"gitlab.alipay-inc.com/afe/mosn/pkg/log"
 	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
 	"net"
 	"runtime/debug"
+	"sync"
+	"syscall"
 <<<<<<< HEAD
 	"time"
 =======
@@ -24,6 +27,7 @@ type listener struct {
 	handOffRestoredDestinationConnections bool
 	cb                                    types.ListenerEventListener
 	rawl                                  *net.TCPListener
+	channelzServer                        *channelz.Server
 }
 
 func NewListener(lc *v2.ListenerConfig) types.Listener {
@@ -35,6 +39,7 @@ func NewListener(lc *v2.ListenerConfig) types.Listener {
 		listenerTag:                           lc.ListenerTag,
 		perConnBufferLimitBytes:               lc.PerConnBufferLimitBytes,
 		handOffRestoredDestinationConnections: lc.HandOffRestoredDestinationConnections,
+		channelzServer:                        channelz.RegisterServer(lc.Name),
 	}
 
 	if lc.InheritListener != nil {
@@ -148,6 +153,7 @@ func (l *listener) SetListenerCallbacks(cb types.ListenerEventListener) {
 
 func (l *listener) Close(lctx context.Context) error {
 	l.cb.OnClose()
+	channelz.RemoveServer(l.channelzServer.ID)
 	return l.rawl.Close()
 }
 
@@ -171,6 +177,9 @@ func (l *listener) accept(lctx context.Context) error {
 		return err
 	}
 
+	socket := l.channelzServer.RegisterSocket(rawc.RemoteAddr().String(), socketFD(rawc))
+	conn := &channelzConn{Conn: rawc, server: l.channelzServer, socket: socket}
+
 	// TODO: use thread pool
 	go func() {
 		defer func() {
@@ -182,8 +191,50 @@ func (l *listener) accept(lctx context.Context) error {
 			}
 		}()
 
-		l.cb.OnAccept(rawc, l.handOffRestoredDestinationConnections)
+		l.cb.OnAccept(conn, l.handOffRestoredDestinationConnections)
 	}()
 
 	return nil
 }
+
+// socketFD extracts the raw file descriptor behind conn via SyscallConn,
+// without duplicating it (unlike (*net.TCPConn).File, which would also
+// flip the original fd back into blocking mode). It returns -1 if conn
+// doesn't expose one, e.g. in tests that pass a non-TCP net.Conn.
+func socketFD(conn net.Conn) int {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return -1
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return -1
+	}
+
+	fd := -1
+	rc.Control(func(f uintptr) {
+		fd = int(f)
+	})
+	return fd
+}
+
+// channelzConn wraps an accepted net.Conn so that whichever downstream
+// owner eventually closes it also removes its channelz.Socket node --
+// accept() itself has no hook into that lifecycle, since the connection
+// is handed off to l.cb.OnAccept to own.
+type channelzConn struct {
+	net.Conn
+
+	server *channelz.Server
+	socket *channelz.Socket
+
+	closeOnce sync.Once
+}
+
+func (c *channelzConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.server.RemoveSocket(c.socket.ID)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,199 @@
+// Package bdp implements a bandwidth-delay-product based flow control
+// window estimator for types.StreamConnection implementations.
+//
+// Instead of announcing a fixed read window derived from the static
+// PerConnBufferLimitBytes on the listener, a StreamConnection that owns
+// an Estimator sends a protocol level probe (an HTTP/2 PING with an
+// 8-byte payload, or a bolt/sofa-rpc heartbeat frame) right before a
+// burst of incoming data, and grows its announced window once the
+// observed bdp outgrows it.
+package bdp
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+const (
+	// gamma is the multiplier applied to a bdp sample before it is
+	// compared against the current window, giving the estimate some
+	// headroom instead of tracking the raw measured value.
+	gamma = 2
+
+	// beta is the fraction of the current window a sample has to exceed
+	// before we bother growing it, so a single noisy RTT can't thrash
+	// the window up and down.
+	beta = 2.0 / 3.0
+
+	initialWindow    = 64 * 1024
+	defaultMaxWindow = 16 * 1024 * 1024
+
+	// ProbePayloadSize is the size of the payload StreamConnection
+	// implementations should attach to the probe they send (the HTTP/2
+	// PING payload, or the padding on a bolt heartbeat).
+	ProbePayloadSize = 8
+)
+
+// Config controls whether a StreamConnection should run BDP-based window
+// growth at all, and how far it's allowed to grow the window.
+type Config struct {
+	// Enabled turns the estimator on. When false the StreamConnection
+	// should keep using its static, configured buffer limit.
+	Enabled bool
+
+	// MaxWindowSize caps the window the estimator may grow to, in
+	// bytes. Zero means use the package default.
+	MaxWindowSize uint32
+}
+
+// Estimator keeps a single smoothed bdp sample for one StreamConnection
+// and decides when its announced flow control window should grow.
+//
+// An Estimator is driven by the StreamConnection that owns it: call
+// OnDataReceived as data is dispatched, OnProbeSent when the probe hits
+// the wire, and OnProbeAck once the peer acks it. It is safe for
+// concurrent use since probes and data can be observed from different
+// goroutines (write side vs. read side of the connection).
+type Estimator struct {
+	cfg Config
+
+	// onUpdate is called with the positive delta to add to the current
+	// window whenever a new sample justifies growing it. Callers wire
+	// this to StreamConnection.UpdateFlowControlWindow.
+	onUpdate func(delta int64)
+
+	mu             sync.Mutex
+	window         uint32
+	sampling       bool
+	sentAt         time.Time
+	sample         uint32
+	aboveWatermark bool
+	listener       types.StreamEventListener
+}
+
+// NewEstimator creates an Estimator starting at the default initial
+// window. onUpdate is invoked every time the estimator decides to grow
+// the window; it may be nil if the caller only cares about watermark
+// state via SetEventListener.
+func NewEstimator(cfg Config, onUpdate func(delta int64)) *Estimator {
+	if cfg.MaxWindowSize == 0 {
+		cfg.MaxWindowSize = defaultMaxWindow
+	}
+	return &Estimator{
+		cfg:      cfg,
+		window:   initialWindow,
+		onUpdate: onUpdate,
+	}
+}
+
+// SetEventListener installs the StreamEventListener whose
+// OnAboveWriteBufferHighWatermark/OnBelowWriteBufferLowWatermark this
+// estimator should drive, instead of those being tied to a fixed
+// constant.
+func (e *Estimator) SetEventListener(l types.StreamEventListener) {
+	e.mu.Lock()
+	e.listener = l
+	e.mu.Unlock()
+}
+
+// OnDataReceived is called for every chunk of data a StreamConnection
+// dispatches to its protocol decoder. It reports whether the caller
+// should send a new probe before continuing, which happens once per
+// sampling round, right before the burst the sample is measuring.
+func (e *Estimator) OnDataReceived(n int) (shouldProbe bool) {
+	if !e.cfg.Enabled || n <= 0 {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.sampling {
+		e.sampling = true
+		e.sample = 0
+		return true
+	}
+
+	e.sample += uint32(n)
+
+	// The current sampling round has already outgrown the announced
+	// window: the peer is sending faster than we're advertising room
+	// for, so raise the high watermark until the window catches up.
+	if !e.aboveWatermark && e.sample > e.window {
+		e.aboveWatermark = true
+		if e.listener != nil {
+			e.listener.OnAboveWriteBufferHighWatermark()
+		}
+	}
+
+	return false
+}
+
+// OnProbeSent records the time the outstanding probe was written to the
+// wire, so OnProbeAck can compute the round trip time.
+func (e *Estimator) OnProbeSent() {
+	e.mu.Lock()
+	e.sentAt = time.Now()
+	e.mu.Unlock()
+}
+
+// OnProbeAck is called once the peer acknowledges the outstanding probe
+// (HTTP/2 PING ack, bolt heartbeat response). It folds the bytes seen
+// since the probe was sent into the smoothed bdp sample and, if the
+// sample grew past beta of the current window, raises the window and
+// reports the delta through onUpdate.
+func (e *Estimator) OnProbeAck() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.sampling {
+		return
+	}
+	e.sampling = false
+
+	if e.sentAt.IsZero() {
+		return
+	}
+	if time.Since(e.sentAt) <= 0 {
+		return
+	}
+
+	sample := e.sample
+	if float64(sample) < beta*float64(e.window) {
+		return
+	}
+
+	bdp := uint32(gamma) * sample
+	if bdp <= e.window {
+		return
+	}
+	if bdp > e.cfg.MaxWindowSize {
+		bdp = e.cfg.MaxWindowSize
+	}
+	if bdp <= e.window {
+		return
+	}
+
+	delta := int64(bdp) - int64(e.window)
+	e.window = bdp
+
+	if e.aboveWatermark && e.sample <= e.window {
+		e.aboveWatermark = false
+		if e.listener != nil {
+			e.listener.OnBelowWriteBufferLowWatermark()
+		}
+	}
+
+	if e.onUpdate != nil {
+		e.onUpdate(delta)
+	}
+}
+
+// Window returns the current announced window size, in bytes.
+func (e *Estimator) Window() uint32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.window
+}
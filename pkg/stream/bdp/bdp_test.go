@@ -0,0 +1,116 @@
+package bdp
+
+import (
+	"testing"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+type recordingListener struct {
+	above int
+	below int
+}
+
+func (l *recordingListener) OnResetStream(reason types.StreamResetReason) {}
+func (l *recordingListener) OnAboveWriteBufferHighWatermark()             { l.above++ }
+func (l *recordingListener) OnBelowWriteBufferLowWatermark()              { l.below++ }
+
+// TestEstimatorGrowsWindowAndUpdatesWatermark drives a full sampling
+// round that outgrows the initial window, and checks the watermark
+// fires on the way up and clears once the window catches up.
+func TestEstimatorGrowsWindowAndUpdatesWatermark(t *testing.T) {
+	var delta int64
+	e := NewEstimator(Config{Enabled: true}, func(d int64) { delta = d })
+	listener := &recordingListener{}
+	e.SetEventListener(listener)
+
+	if probe := e.OnDataReceived(1); !probe {
+		t.Fatal("expected the first chunk of a round to request a probe")
+	}
+	e.OnProbeSent()
+
+	if probe := e.OnDataReceived(int(initialWindow) + 1); probe {
+		t.Fatal("did not expect a second probe mid-round")
+	}
+	if listener.above != 1 {
+		t.Fatalf("expected the high watermark to fire once the sample outgrew the window, got %d", listener.above)
+	}
+
+	e.OnProbeAck()
+
+	if delta <= 0 {
+		t.Fatalf("expected a positive window delta, got %d", delta)
+	}
+	if got := e.Window(); got <= initialWindow {
+		t.Fatalf("expected the window to grow past %d, got %d", initialWindow, got)
+	}
+	if listener.below != 1 {
+		t.Fatalf("expected the low watermark to fire once the window caught back up, got %d", listener.below)
+	}
+}
+
+// TestEstimatorDisabledNeverProbes checks Config.Enabled gates the
+// estimator off entirely, leaving the static window untouched.
+func TestEstimatorDisabledNeverProbes(t *testing.T) {
+	e := NewEstimator(Config{Enabled: false}, nil)
+
+	if probe := e.OnDataReceived(1 << 20); probe {
+		t.Fatal("expected a disabled estimator to never request a probe")
+	}
+	if got := e.Window(); got != initialWindow {
+		t.Fatalf("expected the window to stay at the default, got %d", got)
+	}
+}
+
+type fakeStreamConnection struct {
+	types.StreamConnection
+
+	dispatched  []int
+	pinged      int
+	pingErr     error
+	windowDelta int64
+}
+
+func (f *fakeStreamConnection) Dispatch(buffer types.IoBuffer) {
+	f.dispatched = append(f.dispatched, len(buffer.Bytes()))
+}
+
+func (f *fakeStreamConnection) SendPing() error {
+	f.pinged++
+	return f.pingErr
+}
+
+func (f *fakeStreamConnection) OnGoAway(reason string) {}
+
+func (f *fakeStreamConnection) UpdateFlowControlWindow(streamID string, delta int64) error {
+	f.windowDelta += delta
+	return nil
+}
+
+type fakeBuffer struct{ b []byte }
+
+func (f fakeBuffer) Bytes() []byte { return f.b }
+
+// TestManagedConnectionDispatchProbesAndForwards checks that
+// ManagedConnection sends a probe through the wrapped connection's
+// Keepaliver on the first chunk of a round and always forwards the
+// buffer to the wrapped connection's Dispatch.
+func TestManagedConnectionDispatchProbesAndForwards(t *testing.T) {
+	conn := &fakeStreamConnection{}
+	mc := NewManagedConnection(conn, Config{Enabled: true})
+
+	buf := fakeBuffer{b: make([]byte, 128)}
+	mc.Dispatch(buf)
+
+	if conn.pinged != 1 {
+		t.Fatalf("expected one probe to be sent, got %d", conn.pinged)
+	}
+	if len(conn.dispatched) != 1 || conn.dispatched[0] != 128 {
+		t.Fatalf("expected the buffer to be forwarded to the wrapped connection, got %v", conn.dispatched)
+	}
+
+	mc.OnProbeAck()
+	if conn.windowDelta < 0 {
+		t.Fatalf("did not expect a negative window delta, got %d", conn.windowDelta)
+	}
+}
@@ -0,0 +1,64 @@
+package bdp
+
+import (
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// ManagedConnection wraps a types.StreamConnection with BDP-based flow
+// control: every Dispatch call feeds the estimator instead of the
+// connection announcing a window derived from the static
+// PerConnBufferLimitBytes, and a growing estimate is applied back via
+// types.StreamConnection.UpdateFlowControlWindow.
+//
+// If conn also implements types.Keepaliver, the probe round trip rides
+// on the same ping/ack mechanism the keepalive enforcer uses, rather
+// than inventing a second probe frame; the owner just needs to call
+// OnProbeAck when that ping is acked, the same way it already drives
+// keepalive.Enforcer.OnPingAck.
+type ManagedConnection struct {
+	types.StreamConnection
+
+	est *Estimator
+}
+
+// NewManagedConnection wraps conn with an Estimator configured by cfg.
+// When cfg.Enabled is false, Dispatch is a plain pass-through and conn's
+// existing static window is left alone.
+func NewManagedConnection(conn types.StreamConnection, cfg Config) *ManagedConnection {
+	mc := &ManagedConnection{StreamConnection: conn}
+	mc.est = NewEstimator(cfg, func(delta int64) {
+		// The connection-wide window update; a protocol without that
+		// concept just no-ops it, same as any other caller of this
+		// method.
+		_ = conn.UpdateFlowControlWindow("", delta)
+	})
+	return mc
+}
+
+// Dispatch feeds the incoming buffer's size to the estimator before
+// passing it on to the wrapped connection, sending a probe through the
+// connection's Keepaliver when the estimator starts a new sampling
+// round.
+func (mc *ManagedConnection) Dispatch(buffer types.IoBuffer) {
+	if buffer != nil {
+		if mc.est.OnDataReceived(len(buffer.Bytes())) {
+			if ka, ok := mc.StreamConnection.(types.Keepaliver); ok {
+				if err := ka.SendPing(); err == nil {
+					mc.est.OnProbeSent()
+				}
+			}
+		}
+	}
+	mc.StreamConnection.Dispatch(buffer)
+}
+
+// OnProbeAck must be called by the owner once the probe's ping is
+// acked, the same point keepalive.Enforcer.OnPingAck is called from.
+func (mc *ManagedConnection) OnProbeAck() {
+	mc.est.OnProbeAck()
+}
+
+// Window returns the estimator's current announced window size.
+func (mc *ManagedConnection) Window() uint32 {
+	return mc.est.Window()
+}
@@ -0,0 +1,217 @@
+package interceptor
+
+import (
+	"context"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// Adapter sits at the top of both the decoder and the encoder filter
+// chains for one stream. On the decode side it buffers headers, data
+// and trailers into a Call and runs them through a UnaryInterceptor
+// chain; on the encode side it buffers the response the same way
+// instead of letting it flow to the network, hands it to the decode-side
+// terminal handler, and run re-injects the chain's final response once
+// the chain unwinds. That's
+// what lets an interceptor retry the whole call, or serve a cached
+// response, without needing to implement five filter methods itself.
+type Adapter struct {
+	ctx         context.Context
+	interceptor UnaryInterceptor
+
+	decoderCb types.StreamDecoderFilterCallbacks
+	encoderCb types.StreamEncoderFilterCallbacks
+
+	call    *Call
+	respBuf *Response
+
+	// respCh is (re)armed by terminal at the start of every attempt, so
+	// an interceptor like RetryInterceptor that calls next more than
+	// once gets a fresh channel -- and a fresh delivered -- per attempt
+	// instead of blocking forever on one that deliver already fired on.
+	respCh chan *Response
+
+	// delivered is set once the response captured from the real
+	// upstream has been handed to the decode-side terminal handler for
+	// the current attempt. A decode-side interceptor re-injects the
+	// final response via StreamDecoderFilterCallbacks.EncodeHeaders/
+	// EncodeData/EncodeTrailers, which re-enters this encoder half from
+	// the top of the chain; delivered tells it to let that last pass
+	// through instead of buffering it again. terminal clears it before
+	// every attempt.
+	delivered bool
+}
+
+// NewAdapter creates an Adapter that runs every call on this stream
+// through chain.
+func NewAdapter(ctx context.Context, chain UnaryInterceptor) *Adapter {
+	return &Adapter{
+		ctx:         ctx,
+		interceptor: chain,
+	}
+}
+
+// Decoder returns the types.StreamDecoderFilter half of the adapter.
+func (a *Adapter) Decoder() types.StreamDecoderFilter { return (*decoderHalf)(a) }
+
+// Encoder returns the types.StreamEncoderFilter half of the adapter.
+func (a *Adapter) Encoder() types.StreamEncoderFilter { return (*encoderHalf)(a) }
+
+type decoderHalf Adapter
+
+func (d *decoderHalf) OnDestroy() {}
+
+func (d *decoderHalf) SetDecoderFilterCallbacks(cb types.StreamDecoderFilterCallbacks) {
+	d.decoderCb = cb
+}
+
+func (d *decoderHalf) DecodeHeaders(headers map[string]string, endStream bool) types.FilterHeadersStatus {
+	d.call = &Call{cb: d.decoderCb, ReqHeaders: headers}
+	if endStream {
+		d.run()
+	}
+	return types.FilterHeadersStatusStopIteration
+}
+
+func (d *decoderHalf) DecodeData(buf types.IoBuffer, endStream bool) types.FilterDataStatus {
+	d.call.ReqData = buf
+	if endStream {
+		d.run()
+	}
+	return types.FilterDataStatusStopIterationNoBuffer
+}
+
+func (d *decoderHalf) DecodeTrailers(trailers map[string]string) types.FilterTrailersStatus {
+	d.call.ReqTrailers = trailers
+	d.run()
+	return types.FilterTrailersStatusStopIteration
+}
+
+// run invokes the interceptor chain for the buffered call and, once it
+// returns, writes the final response back into the real encoder
+// callbacks -- which is also how a short-circuiting interceptor (serving
+// from cache, failing a retry budget) produces a response without ever
+// letting the request reach the upstream.
+func (d *decoderHalf) run() {
+	a := (*Adapter)(d)
+
+	resp, err := a.interceptor(a.ctx, a.call, a.terminal)
+	if resp == nil {
+		resp = &Response{Err: err}
+	} else if err != nil {
+		resp.Err = err
+	}
+
+	if d.decoderCb == nil {
+		return
+	}
+
+	// A bare error with nothing buffered (TimeoutInterceptor after its
+	// deadline, RetryInterceptor exhausting attempts against a cancelled
+	// ctx) has no frames to re-inject; without resetting the stream here
+	// it would just hang forever from the client's perspective, since
+	// nothing else is ever going to call EncodeHeaders/ResetStream for
+	// it.
+	if resp.Headers == nil && resp.Data == nil && resp.Trailers == nil {
+		if resp.Err != nil {
+			d.decoderCb.ResetStream()
+		}
+		return
+	}
+
+	// Re-inject through StreamDecoderFilterCallbacks, which starts a
+	// fresh pass over the encoder filter chain from the top; by now
+	// a.delivered is set, so this encoder half lets it straight through
+	// instead of buffering it again.
+	if resp.Headers != nil {
+		d.decoderCb.EncodeHeaders(resp.Headers, resp.Data == nil && resp.Trailers == nil)
+	}
+	if resp.Data != nil {
+		d.decoderCb.EncodeData(resp.Data, resp.Trailers == nil)
+	}
+	if resp.Trailers != nil {
+		d.decoderCb.EncodeTrailers(resp.Trailers)
+	}
+}
+
+// terminal is the innermost UnaryHandler: it lets the request continue
+// down the rest of the decode filter chain (routing, upstream dispatch)
+// and blocks until the paired encoder half captures the response that
+// comes back. It re-arms respCh and delivered before every call, so an
+// interceptor that calls it more than once (RetryInterceptor) gets a
+// fresh wait per attempt; ContinueDecoding replays the same request
+// that's still sitting in the filter manager's decode buffer, since
+// nothing between here and terminal ever consumed it.
+func (a *Adapter) terminal(ctx context.Context, call *Call) (*Response, error) {
+	ch := make(chan *Response, 1)
+	a.respCh = ch
+	a.delivered = false
+
+	a.decoderCb.ContinueDecoding()
+
+	select {
+	case resp := <-ch:
+		return resp, resp.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type encoderHalf Adapter
+
+func (e *encoderHalf) OnDestroy() {}
+
+func (e *encoderHalf) SetEncoderFilterCallbacks(cb types.StreamEncoderFilterCallbacks) {
+	e.encoderCb = cb
+}
+
+func (e *encoderHalf) EncodeHeaders(headers interface{}, endStream bool) types.FilterHeadersStatus {
+	if (*Adapter)(e).delivered {
+		return types.FilterHeadersStatusContinue
+	}
+	e.buffer().Headers = headers
+	if endStream {
+		e.deliver()
+	}
+	return types.FilterHeadersStatusStopIteration
+}
+
+func (e *encoderHalf) EncodeData(buf types.IoBuffer, endStream bool) types.FilterDataStatus {
+	if (*Adapter)(e).delivered {
+		return types.FilterDataStatusContinue
+	}
+	e.buffer().Data = buf
+	if endStream {
+		e.deliver()
+	}
+	return types.FilterDataStatusStopIterationNoBuffer
+}
+
+func (e *encoderHalf) EncodeTrailers(trailers map[string]string) types.FilterTrailersStatus {
+	if (*Adapter)(e).delivered {
+		return types.FilterTrailersStatusContinue
+	}
+	e.buffer().Trailers = trailers
+	e.deliver()
+	return types.FilterTrailersStatusStopIteration
+}
+
+func (e *encoderHalf) buffer() *Response {
+	a := (*Adapter)(e)
+	if a.respBuf == nil {
+		a.respBuf = &Response{}
+	}
+	return a.respBuf
+}
+
+// deliver hands the fully assembled response -- headers, data and
+// trailers merged across however many frames the protocol split it
+// into -- to the decode-side terminal handler blocked in
+// Adapter.terminal.
+func (e *encoderHalf) deliver() {
+	a := (*Adapter)(e)
+	resp := a.respBuf
+	a.respBuf = nil
+	a.delivered = true
+	a.respCh <- resp
+}
@@ -0,0 +1,136 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// fakeDecoderCb is a minimal types.StreamDecoderFilterCallbacks that
+// records what the adapter does with it, so tests can drive
+// decoderHalf/encoderHalf without a real filter manager. onContinue, if
+// set, fires synchronously from ContinueDecoding -- the only place this
+// adapter ever triggers the (simulated) downstream dispatch from.
+type fakeDecoderCb struct {
+	continued      int
+	resetCalled    bool
+	encodedHeaders []interface{}
+	onContinue     func()
+}
+
+func (f *fakeDecoderCb) Connection() types.Connection   { return nil }
+func (f *fakeDecoderCb) ResetStream()                   { f.resetCalled = true }
+func (f *fakeDecoderCb) Route() types.Route             { return nil }
+func (f *fakeDecoderCb) StreamId() string               { return "1" }
+func (f *fakeDecoderCb) RequestInfo() types.RequestInfo { return nil }
+func (f *fakeDecoderCb) ContinueDecoding() {
+	f.continued++
+	if f.onContinue != nil {
+		f.onContinue()
+	}
+}
+func (f *fakeDecoderCb) DecodingBuffer() types.IoBuffer                    { return nil }
+func (f *fakeDecoderCb) AddDecodedData(buf types.IoBuffer, streaming bool) {}
+func (f *fakeDecoderCb) EncodeHeaders(headers interface{}, endStream bool) {
+	f.encodedHeaders = append(f.encodedHeaders, headers)
+}
+func (f *fakeDecoderCb) EncodeData(buf types.IoBuffer, endStream bool) {}
+func (f *fakeDecoderCb) EncodeTrailers(trailers map[string]string)     {}
+func (f *fakeDecoderCb) OnDecoderFilterAboveWriteBufferHighWatermark() {}
+func (f *fakeDecoderCb) OnDecoderFilterBelowWriteBufferLowWatermark()  {}
+func (f *fakeDecoderCb) AddDownstreamWatermarkCallbacks(cb types.DownstreamWatermarkEventListener) {
+}
+func (f *fakeDecoderCb) RemoveDownstreamWatermarkCallbacks(cb types.DownstreamWatermarkEventListener) {
+}
+func (f *fakeDecoderCb) SetDecoderBufferLimit(limit uint32) {}
+func (f *fakeDecoderCb) DecoderBufferLimit() uint32         { return 0 }
+
+// TestDecoderHalfRunResetsStreamOnBareTimeoutError reproduces the hang:
+// TimeoutInterceptor's deadline expires before anything ever answers
+// ContinueDecoding, so terminal returns a bare error with no buffered
+// frames. run must reset the stream instead of silently doing nothing.
+func TestDecoderHalfRunResetsStreamOnBareTimeoutError(t *testing.T) {
+	cb := &fakeDecoderCb{}
+	a := NewAdapter(context.Background(), TimeoutInterceptor(10*time.Millisecond))
+	dh := a.Decoder().(*decoderHalf)
+	dh.SetDecoderFilterCallbacks(cb)
+
+	dh.DecodeHeaders(map[string]string{}, true)
+
+	if cb.continued != 1 {
+		t.Fatalf("expected ContinueDecoding to be called once, got %d", cb.continued)
+	}
+	if !cb.resetCalled {
+		t.Fatal("expected ResetStream to be called when the call times out with nothing buffered")
+	}
+	if len(cb.encodedHeaders) != 0 {
+		t.Fatalf("expected no EncodeHeaders call, got %d", len(cb.encodedHeaders))
+	}
+}
+
+// TestDecoderHalfRunDeliversRealResponse exercises the happy path: the
+// encoder half captures the real response frames and run re-injects
+// them through decoderCb once the chain unwinds.
+func TestDecoderHalfRunDeliversRealResponse(t *testing.T) {
+	cb := &fakeDecoderCb{}
+	a := NewAdapter(context.Background(), ChainUnaryInterceptor())
+	dh := a.Decoder().(*decoderHalf)
+	eh := a.Encoder().(*encoderHalf)
+	dh.SetDecoderFilterCallbacks(cb)
+
+	cb.onContinue = func() {
+		eh.EncodeHeaders(map[string]string{"status": "200"}, true)
+	}
+
+	dh.DecodeHeaders(map[string]string{}, true)
+
+	if len(cb.encodedHeaders) != 1 {
+		t.Fatalf("expected the real response to be re-injected once, got %d", len(cb.encodedHeaders))
+	}
+}
+
+// TestTerminalReArmsPerAttempt exercises RetryInterceptor: the first
+// attempt reaches terminal and consumes its respCh and delivered, then
+// is rejected; the second attempt must still get a respCh that its own
+// encoder delivery reaches -- not one left over (and already consumed)
+// from the prior attempt.
+func TestTerminalReArmsPerAttempt(t *testing.T) {
+	cb := &fakeDecoderCb{}
+	attempts := 0
+
+	a := NewAdapter(context.Background(), func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		attempts++
+		resp, err := next(ctx, call)
+		if attempts == 1 && err == nil {
+			// The first attempt's response arrives through terminal
+			// but is treated as retryable.
+			return resp, errors.New("upstream returned a retryable status")
+		}
+		return resp, err
+	})
+	dh := a.Decoder().(*decoderHalf)
+	eh := a.Encoder().(*encoderHalf)
+	dh.SetDecoderFilterCallbacks(cb)
+
+	retry := RetryInterceptor(2)
+	a.interceptor = ChainUnaryInterceptor(retry, a.interceptor)
+
+	cb.onContinue = func() {
+		eh.EncodeHeaders(map[string]string{"status": "200"}, true)
+	}
+
+	dh.DecodeHeaders(map[string]string{}, true)
+
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if cb.continued != 2 {
+		t.Fatalf("expected ContinueDecoding to be called once per attempt, got %d", cb.continued)
+	}
+	if len(cb.encodedHeaders) != 1 {
+		t.Fatalf("expected the second attempt's response to be re-injected once, got %d", len(cb.encodedHeaders))
+	}
+}
@@ -0,0 +1,75 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/log"
+)
+
+// TimeoutInterceptor fails the call with context.DeadlineExceeded if it
+// hasn't produced a response within d.
+func TimeoutInterceptor(d time.Duration) UnaryInterceptor {
+	return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return next(ctx, call)
+	}
+}
+
+// RetryInterceptor re-runs the call up to maxAttempts times (including
+// the first) as long as next returns a non-nil error, and returns the
+// last attempt's result otherwise.
+func RetryInterceptor(maxAttempts int) UnaryInterceptor {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		var resp *Response
+		var err error
+
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			resp, err = next(ctx, call)
+			if err == nil {
+				return resp, nil
+			}
+			if ctx.Err() != nil {
+				return resp, err
+			}
+		}
+		return resp, err
+	}
+}
+
+// MetricsInterceptor calls record with the call's Route/StreamId,
+// elapsed latency and final error (nil on success) once the call
+// completes.
+func MetricsInterceptor(record func(call *Call, d time.Duration, err error)) UnaryInterceptor {
+	return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, call)
+		if record != nil {
+			record(call, time.Since(start), err)
+		}
+		return resp, err
+	}
+}
+
+// AccessLogInterceptor logs one line per call: the stream ID, the
+// elapsed time and the final status ("OK", or the error text). It does
+// not read the stream's RequestInfo or Route.
+func AccessLogInterceptor() UnaryInterceptor {
+	return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, call)
+
+		status := "OK"
+		if err != nil {
+			status = err.Error()
+		}
+		log.DefaultLogger.Println(call.StreamId(), time.Since(start), status)
+
+		return resp, err
+	}
+}
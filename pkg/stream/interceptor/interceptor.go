@@ -0,0 +1,112 @@
+// Package interceptor adds a higher-level, gRPC-style interceptor API
+// on top of the per-event types.StreamEncoderFilter/StreamDecoderFilter
+// model, for cross-event logic ("log at end of call with final status",
+// "retry the whole request", "cache full response then forward") that's
+// awkward to express as a five-method filter.
+package interceptor
+
+import (
+	"context"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// Call is the synthetic request/response object an Adapter builds out
+// of the buffered headers/data/trailers of a unary call. It exposes the
+// same Route/StreamId/RequestInfo surface a StreamFilterCallbacks
+// would.
+type Call struct {
+	cb types.StreamFilterCallbacks
+
+	ReqHeaders  interface{}
+	ReqData     types.IoBuffer
+	ReqTrailers map[string]string
+}
+
+func (c *Call) Route() types.Route             { return c.cb.Route() }
+func (c *Call) StreamId() string               { return c.cb.StreamId() }
+func (c *Call) RequestInfo() types.RequestInfo { return c.cb.RequestInfo() }
+func (c *Call) Connection() types.Connection   { return c.cb.Connection() }
+
+// Response is the synthetic response a unary call produced, built the
+// same way as Call but from the encode side.
+type Response struct {
+	Headers  interface{}
+	Data     types.IoBuffer
+	Trailers map[string]string
+	Err      error
+}
+
+// UnaryHandler is the next step in a unary interceptor chain; the
+// innermost one continues the actual StreamDecoderFilter/
+// StreamEncoderFilter pipeline and waits for the response it produces.
+type UnaryHandler func(ctx context.Context, call *Call) (*Response, error)
+
+// UnaryInterceptor wraps a UnaryHandler. Calling next invokes the rest
+// of the chain (and, eventually, the real upstream call); an
+// interceptor that doesn't call next at all short-circuits the call
+// entirely, e.g. to serve from a cache.
+type UnaryInterceptor func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error)
+
+// ChainUnaryInterceptor composes is into a single UnaryInterceptor that
+// runs them in order, each wrapping the next, with the last one
+// wrapping the terminal handler passed to the composed interceptor.
+func ChainUnaryInterceptor(is ...UnaryInterceptor) UnaryInterceptor {
+	if len(is) == 0 {
+		return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+			return next(ctx, call)
+		}
+	}
+
+	return func(ctx context.Context, call *Call, next UnaryHandler) (*Response, error) {
+		chained := next
+		for i := len(is) - 1; i >= 0; i-- {
+			interceptor := is[i]
+			cur := chained
+			chained = func(ctx context.Context, call *Call) (*Response, error) {
+				return interceptor(ctx, call, cur)
+			}
+		}
+		return chained(ctx, call)
+	}
+}
+
+// StreamInfo is the non-buffered counterpart of Call, for streaming
+// interceptors that want route/stream metadata without forcing the
+// whole body to be buffered in memory.
+type StreamInfo struct {
+	cb types.StreamFilterCallbacks
+}
+
+func (s StreamInfo) Route() types.Route             { return s.cb.Route() }
+func (s StreamInfo) StreamId() string               { return s.cb.StreamId() }
+func (s StreamInfo) RequestInfo() types.RequestInfo { return s.cb.RequestInfo() }
+
+// StreamHandler is the next step in a streaming interceptor chain.
+type StreamHandler func(ctx context.Context, info StreamInfo) error
+
+// StreamInterceptor wraps a StreamHandler for the streaming (non
+// request/response buffered) case.
+type StreamInterceptor func(ctx context.Context, info StreamInfo, next StreamHandler) error
+
+// ChainStreamInterceptor composes is the same way ChainUnaryInterceptor
+// does, for the streaming case.
+func ChainStreamInterceptor(is ...StreamInterceptor) StreamInterceptor {
+	if len(is) == 0 {
+		return func(ctx context.Context, info StreamInfo, next StreamHandler) error {
+			return next(ctx, info)
+		}
+	}
+
+	return func(ctx context.Context, info StreamInfo, next StreamHandler) error {
+		chained := next
+		for i := len(is) - 1; i >= 0; i-- {
+			interceptor := is[i]
+			cur := chained
+			chained = func(ctx context.Context, info StreamInfo) error {
+				return interceptor(ctx, info, cur)
+			}
+		}
+		return chained(ctx, info)
+	}
+}
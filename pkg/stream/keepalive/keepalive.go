@@ -0,0 +1,232 @@
+// Package keepalive enforces a types.KeepaliveParameters policy against
+// a types.StreamConnection that implements types.Keepaliver, without
+// either side needing to know whether the underlying protocol is
+// HTTP/2 or bolt/sofa-rpc.
+package keepalive
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/channelz"
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// ActiveStreamCounter is implemented by the StreamConnection so the
+// enforcer can tell whether MaxConnectionIdle should start counting
+// down, and how many streams are still in flight when MaxConnectionAge
+// is reached.
+type ActiveStreamCounter interface {
+	ActiveStreams() int
+}
+
+// Enforcer drives a client-side or server-side keepalive policy for one
+// StreamConnection. Callers create one per connection, call OnRead on
+// every read, and Close it when the connection goes away.
+type Enforcer struct {
+	params  types.KeepaliveParameters
+	conn    types.Keepaliver
+	counter ActiveStreamCounter
+	socket  *channelz.Socket
+
+	// onTimeout is invoked with the reset reason whenever the enforcer
+	// tears the connection down: StreamConnectionFailed when a ping goes
+	// unacked past Timeout, StreamConnectionTermination after a GoAway
+	// for ping abuse or MaxConnectionIdle, and StreamKeepaliveTimeout
+	// when MaxConnectionAgeGrace elapses after MaxConnectionAge.
+	onTimeout func(reason types.StreamResetReason)
+
+	mu               sync.Mutex
+	closed           bool
+	lastRead         time.Time
+	pingOutstanding  bool
+	pingSentAt       time.Time
+	connStart        time.Time
+	lastPingFromPeer time.Time
+	peerPingStrikes  int
+
+	stopCh chan struct{}
+}
+
+// NewEnforcer starts enforcing params against conn. counter may be nil
+// if the caller doesn't want MaxConnectionIdle enforcement. socket may
+// be nil; when set, keepalives sent are reported through channelz.
+func NewEnforcer(params types.KeepaliveParameters, conn types.Keepaliver, counter ActiveStreamCounter, socket *channelz.Socket, onTimeout func(reason types.StreamResetReason)) *Enforcer {
+	e := &Enforcer{
+		params:    params,
+		conn:      conn,
+		counter:   counter,
+		socket:    socket,
+		onTimeout: onTimeout,
+		connStart: time.Now(),
+		lastRead:  time.Now(),
+		stopCh:    make(chan struct{}),
+	}
+
+	go e.clientLoop()
+	if params.MaxConnectionIdle > 0 || params.MaxConnectionAge > 0 {
+		go e.serverLoop()
+	}
+
+	return e
+}
+
+// OnRead must be called every time data is read off the connection; it
+// resets the Time-based idle timer and acks any outstanding ping.
+func (e *Enforcer) OnRead() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastRead = time.Now()
+	e.pingOutstanding = false
+}
+
+// OnPingAck must be called when the peer acks our outstanding ping.
+func (e *Enforcer) OnPingAck() {
+	e.mu.Lock()
+	e.pingOutstanding = false
+	e.mu.Unlock()
+}
+
+// OnPingReceived is called by the server side every time the client
+// sends a keepalive ping, to enforce MinTime between pings. Once the
+// client has exceeded the rate twice, the enforcer sends GoAway with an
+// "enhance_your_calm" reason and closes the connection.
+func (e *Enforcer) OnPingReceived() {
+	if e.params.MinTime <= 0 {
+		return
+	}
+
+	e.mu.Lock()
+	now := time.Now()
+	tooSoon := !e.lastPingFromPeer.IsZero() && now.Sub(e.lastPingFromPeer) < e.params.MinTime
+	e.lastPingFromPeer = now
+	if tooSoon {
+		e.peerPingStrikes++
+	} else {
+		e.peerPingStrikes = 0
+	}
+	tripped := e.peerPingStrikes >= 2
+	if tripped {
+		e.peerPingStrikes = 0
+	}
+	e.mu.Unlock()
+
+	if tripped {
+		e.conn.OnGoAway("enhance_your_calm")
+		e.fail(types.StreamConnectionTermination)
+	}
+}
+
+// Close stops the enforcer's background timers.
+func (e *Enforcer) Close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	e.mu.Unlock()
+	close(e.stopCh)
+}
+
+// clientLoop sends a ping after Time of read inactivity and fails the
+// connection if it isn't acked within Timeout.
+func (e *Enforcer) clientLoop() {
+	if e.params.Time <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.params.Time)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.checkClient()
+		}
+	}
+}
+
+func (e *Enforcer) checkClient() {
+	e.mu.Lock()
+
+	if e.counter != nil && !e.params.PermitWithoutStream && e.counter.ActiveStreams() == 0 {
+		e.mu.Unlock()
+		return
+	}
+
+	if e.pingOutstanding {
+		timedOut := time.Since(e.pingSentAt) > e.params.Timeout
+		e.mu.Unlock()
+		if timedOut {
+			e.fail(types.StreamConnectionFailed)
+		}
+		return
+	}
+
+	idleFor := time.Since(e.lastRead)
+	if idleFor < e.params.Time {
+		e.mu.Unlock()
+		return
+	}
+
+	e.pingOutstanding = true
+	e.pingSentAt = time.Now()
+	e.mu.Unlock()
+
+	if err := e.conn.SendPing(); err == nil && e.socket != nil {
+		e.socket.KeepAliveSent()
+	}
+}
+
+func (e *Enforcer) fail(reason types.StreamResetReason) {
+	if e.socket != nil {
+		e.socket.ResetStream(string(reason))
+	}
+	if e.onTimeout != nil {
+		e.onTimeout(reason)
+	}
+}
+
+// serverLoop enforces MaxConnectionIdle and MaxConnectionAge.
+func (e *Enforcer) serverLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	goAwaySent := false
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			age := time.Since(e.connStart)
+			idleFor := time.Since(e.lastRead)
+			activeStreams := 0
+			if e.counter != nil {
+				activeStreams = e.counter.ActiveStreams()
+			}
+			e.mu.Unlock()
+
+			if e.params.MaxConnectionIdle > 0 && e.counter != nil && activeStreams == 0 && idleFor > e.params.MaxConnectionIdle {
+				e.conn.OnGoAway("max_connection_idle")
+				e.fail(types.StreamConnectionTermination)
+				return
+			}
+
+			if e.params.MaxConnectionAge > 0 && age > e.params.MaxConnectionAge {
+				if !goAwaySent {
+					e.conn.OnGoAway("max_connection_age")
+					goAwaySent = true
+				}
+				if age > e.params.MaxConnectionAge+e.params.MaxConnectionAgeGrace {
+					e.fail(types.StreamKeepaliveTimeout)
+					return
+				}
+			}
+		}
+	}
+}
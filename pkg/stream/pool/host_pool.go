@@ -0,0 +1,147 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// hostPool manages every connection the owning pool has open to a
+// single (host, protocol).
+type hostPool struct {
+	cfg      Config
+	dial     Dialer
+	host     string
+	protocol types.Protocol
+
+	mu      sync.Mutex
+	conns   []*pooledConn
+	dialing int
+	waiters []chan dialResult
+	closed  bool
+}
+
+type dialResult struct {
+	conn *pooledConn
+	err  error
+}
+
+func newHostPool(cfg Config, dial Dialer, host string, protocol types.Protocol) *hostPool {
+	return &hostPool{cfg: cfg, dial: dial, host: host, protocol: protocol}
+}
+
+// getConn implements the reserve-then-create protocol described on
+// ConnPool.GetConn.
+func (hp *hostPool) getConn(ctx context.Context) (types.ClientStreamConnection, error) {
+	hp.mu.Lock()
+
+	if hp.closed {
+		hp.mu.Unlock()
+		return nil, fmt.Errorf("pool: closed for %s", hp.host)
+	}
+
+	for _, c := range hp.conns {
+		if c.reserve() {
+			hp.mu.Unlock()
+			return c, nil
+		}
+	}
+
+	if hp.dialing > 0 {
+		// A dial is already in flight for this host: queue behind it
+		// instead of starting a second one.
+		waitCh := make(chan dialResult, 1)
+		hp.waiters = append(hp.waiters, waitCh)
+		hp.mu.Unlock()
+
+		select {
+		case res := <-waitCh:
+			if res.err != nil {
+				return nil, res.err
+			}
+			if res.conn.reserve() {
+				return res.conn, nil
+			}
+			// Lost the race for the slot the dial produced; fall
+			// through and try the whole thing again.
+			return hp.getConn(ctx)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if len(hp.conns) >= hp.cfg.MaxConnsPerHost {
+		hp.mu.Unlock()
+		return nil, fmt.Errorf("pool: max conns per host (%d) reached for %s", hp.cfg.MaxConnsPerHost, hp.host)
+	}
+
+	hp.dialing++
+	hp.mu.Unlock()
+
+	return hp.dialAndReserve(ctx)
+}
+
+func (hp *hostPool) dialAndReserve(ctx context.Context) (types.ClientStreamConnection, error) {
+	pc := newPooledConn(hp, hp.cfg.DefaultMaxConcurrentStreams)
+
+	conn, err := hp.dial(ctx, hp.host, hp.protocol, pc)
+
+	hp.mu.Lock()
+	hp.dialing--
+	waiters := hp.waiters
+	hp.waiters = nil
+	hp.mu.Unlock()
+
+	if err != nil {
+		for _, w := range waiters {
+			w <- dialResult{err: err}
+		}
+		return nil, err
+	}
+
+	pc.ClientStreamConnection = conn
+	pc.reserve()
+
+	hp.mu.Lock()
+	hp.conns = append(hp.conns, pc)
+	hp.mu.Unlock()
+
+	for _, w := range waiters {
+		w <- dialResult{conn: pc}
+	}
+
+	return pc, nil
+}
+
+// markBad marks conn as bad if it belongs to this hostPool, reporting
+// whether it did.
+func (hp *hostPool) markBad(conn types.ClientStreamConnection) bool {
+	pc, ok := conn.(*pooledConn)
+	if !ok {
+		return false
+	}
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	for _, c := range hp.conns {
+		if c == pc {
+			pc.markBad()
+			return true
+		}
+	}
+	return false
+}
+
+func (hp *hostPool) close() {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.closed = true
+	for _, c := range hp.conns {
+		c.GoAway()
+	}
+	hp.conns = nil
+}
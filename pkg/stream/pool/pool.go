@@ -0,0 +1,120 @@
+// Package pool multiplexes types.ClientStreamConnection instances per
+// (host, protocol), analogous to an HTTP/2 ClientConnPool: instead of
+// dialing a new upstream connection per request, callers reserve a
+// stream slot on an existing connection and only dial a new one once
+// every existing connection is full.
+package pool
+
+import (
+	"context"
+	"sync"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// Dialer creates a new ClientStreamConnection to host over protocol,
+// installing listener as the connection's StreamConnectionEventListener
+// so the pool learns about GoAway.
+type Dialer func(ctx context.Context, host string, protocol types.Protocol, listener types.StreamConnectionEventListener) (types.ClientStreamConnection, error)
+
+// MaxStreamsHinter is implemented by ClientStreamConnections that learn
+// a MaxConcurrentStreams hint from the remote (an HTTP/2 SETTINGS frame,
+// a bolt handshake response). Until it reports a nonzero value, the
+// pool falls back to Config.DefaultMaxConcurrentStreams.
+type MaxStreamsHinter interface {
+	MaxConcurrentStreams() uint32
+}
+
+// ConnPool manages pooled, multiplexed connections to upstream hosts.
+type ConnPool interface {
+	// GetConn reserves a stream slot on an existing connection to host,
+	// dialing a new one if every existing connection's hinted capacity
+	// is exhausted, up to MaxConnsPerHost.
+	GetConn(ctx context.Context, host string, protocol types.Protocol) (types.ClientStreamConnection, error)
+
+	// MarkBad removes conn from rotation; in-flight streams on it are
+	// unaffected, but it is never handed out again.
+	MarkBad(conn types.ClientStreamConnection)
+
+	Close()
+}
+
+// Config tunes pool-wide limits.
+type Config struct {
+	// MaxConnsPerHost caps how many connections the pool keeps open to
+	// a single (host, protocol) at once.
+	MaxConnsPerHost int
+
+	// DefaultMaxConcurrentStreams is used until a connection's
+	// MaxStreamsHinter reports a value learned from the remote.
+	DefaultMaxConcurrentStreams uint32
+}
+
+type hostKey struct {
+	host     string
+	protocol types.Protocol
+}
+
+type pool struct {
+	cfg  Config
+	dial Dialer
+
+	mu    sync.Mutex
+	hosts map[hostKey]*hostPool
+}
+
+// NewConnPool creates a ConnPool that dials new connections via dial.
+func NewConnPool(cfg Config, dial Dialer) ConnPool {
+	if cfg.MaxConnsPerHost <= 0 {
+		cfg.MaxConnsPerHost = 8
+	}
+	if cfg.DefaultMaxConcurrentStreams == 0 {
+		cfg.DefaultMaxConcurrentStreams = 100
+	}
+	return &pool{
+		cfg:   cfg,
+		dial:  dial,
+		hosts: map[hostKey]*hostPool{},
+	}
+}
+
+func (p *pool) hostPoolFor(host string, protocol types.Protocol) *hostPool {
+	k := hostKey{host, protocol}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hp, ok := p.hosts[k]
+	if !ok {
+		hp = newHostPool(p.cfg, p.dial, host, protocol)
+		p.hosts[k] = hp
+	}
+	return hp
+}
+
+func (p *pool) GetConn(ctx context.Context, host string, protocol types.Protocol) (types.ClientStreamConnection, error) {
+	return p.hostPoolFor(host, protocol).getConn(ctx)
+}
+
+func (p *pool) MarkBad(conn types.ClientStreamConnection) {
+	p.mu.Lock()
+	hps := make([]*hostPool, 0, len(p.hosts))
+	for _, hp := range p.hosts {
+		hps = append(hps, hp)
+	}
+	p.mu.Unlock()
+
+	for _, hp := range hps {
+		if hp.markBad(conn) {
+			return
+		}
+	}
+}
+
+func (p *pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, hp := range p.hosts {
+		hp.close()
+	}
+}
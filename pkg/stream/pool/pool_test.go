@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+type fakeStream struct {
+	listeners []types.StreamEventListener
+}
+
+func (s *fakeStream) AddEventListener(l types.StreamEventListener) {
+	s.listeners = append(s.listeners, l)
+}
+func (s *fakeStream) RemoveEventListener(l types.StreamEventListener) {}
+func (s *fakeStream) ResetStream(reason types.StreamResetReason)      {}
+func (s *fakeStream) ReadDisable(disable bool)                        {}
+
+func (s *fakeStream) reset(reason types.StreamResetReason) {
+	for _, l := range s.listeners {
+		l.OnResetStream(reason)
+	}
+}
+
+type fakeEncoder struct {
+	stream *fakeStream
+}
+
+func (e *fakeEncoder) EncodeHeaders(headers interface{}, endStream bool) error { return nil }
+func (e *fakeEncoder) EncodeData(data types.IoBuffer, endStream bool) error    { return nil }
+func (e *fakeEncoder) EncodeTrailers(trailers map[string]string) error         { return nil }
+func (e *fakeEncoder) GetStream() types.Stream                                 { return e.stream }
+
+type fakeClientConn struct {
+	maxConcurrentStreams uint32
+}
+
+func (c *fakeClientConn) Dispatch(buffer types.IoBuffer)                             {}
+func (c *fakeClientConn) Protocol() types.Protocol                                   { return "fake" }
+func (c *fakeClientConn) GoAway()                                                    {}
+func (c *fakeClientConn) OnUnderlyingConnectionAboveWriteBufferHighWatermark()       {}
+func (c *fakeClientConn) OnUnderlyingConnectionBelowWriteBufferLowWatermark()        {}
+func (c *fakeClientConn) UpdateFlowControlWindow(streamID string, delta int64) error { return nil }
+func (c *fakeClientConn) MaxConcurrentStreams() uint32                               { return c.maxConcurrentStreams }
+func (c *fakeClientConn) NewStream(streamID string, responseDecoder types.StreamDecoder) types.StreamEncoder {
+	return &fakeEncoder{stream: &fakeStream{}}
+}
+
+func newPool(t *testing.T, maxStreams uint32) (ConnPool, *fakeClientConn) {
+	t.Helper()
+	conn := &fakeClientConn{maxConcurrentStreams: maxStreams}
+	p := NewConnPool(Config{MaxConnsPerHost: 1, DefaultMaxConcurrentStreams: 1}, func(ctx context.Context, host string, protocol types.Protocol, listener types.StreamConnectionEventListener) (types.ClientStreamConnection, error) {
+		return conn, nil
+	})
+	return p, conn
+}
+
+type nopDecoder struct{}
+
+func (nopDecoder) OnDecodeHeaders(headers map[string]string, endStream bool) {}
+func (nopDecoder) OnDecodeData(data types.IoBuffer, endStream bool)          {}
+func (nopDecoder) OnDecodeTrailers(trailers map[string]string)               {}
+
+// TestGetConnReservesAndReleasesSlots checks that a stream ending
+// releases its slot, freeing the connection back up for reuse, and that
+// a connection at its hinted MaxConcurrentStreams isn't handed out
+// again until then.
+func TestGetConnReservesAndReleasesSlots(t *testing.T) {
+	p, _ := newPool(t, 1)
+
+	conn, err := p.GetConn(context.Background(), "host", "fake")
+	if err != nil {
+		t.Fatalf("GetConn: %v", err)
+	}
+
+	if _, err := p.GetConn(context.Background(), "host", "fake"); err == nil {
+		t.Fatal("expected GetConn to fail: the only connection is full and MaxConnsPerHost is 1")
+	}
+
+	encoder := conn.NewStream("1", nopDecoder{})
+	encoder.GetStream().(*fakeStream).reset(types.StreamLocalReset)
+
+	conn2, err := p.GetConn(context.Background(), "host", "fake")
+	if err != nil {
+		t.Fatalf("GetConn after release: %v", err)
+	}
+	if conn2 != conn {
+		t.Fatal("expected the released slot on the original connection to be reused")
+	}
+}
+
+// TestConnectionTerminationMarksConnBad checks that a reset reporting
+// StreamConnectionTermination takes the whole connection out of
+// rotation, the same as StreamConnectionFailed, instead of being
+// treated like an ordinary per-stream reset.
+func TestConnectionTerminationMarksConnBad(t *testing.T) {
+	p, _ := newPool(t, 1)
+
+	conn, err := p.GetConn(context.Background(), "host", "fake")
+	if err != nil {
+		t.Fatalf("GetConn: %v", err)
+	}
+
+	encoder := conn.NewStream("1", nopDecoder{})
+	encoder.GetStream().(*fakeStream).reset(types.StreamConnectionTermination)
+
+	if _, err := p.GetConn(context.Background(), "host", "fake"); err == nil {
+		t.Fatal("expected the terminated connection to not be handed out again")
+	}
+}
@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"gitlab.alipay-inc.com/afe/mosn/pkg/types"
+)
+
+// pooledConn wraps a dialed types.ClientStreamConnection with the slot
+// accounting the pool needs. It embeds the real connection so callers
+// that only know about types.ClientStreamConnection get the real
+// Dispatch/Protocol/GoAway/watermark behavior for free; only NewStream
+// is intercepted, to release the reserved slot once the stream ends.
+//
+// It also implements types.StreamConnectionEventListener so the pool
+// can react to the remote sending GoAway.
+type pooledConn struct {
+	types.ClientStreamConnection
+
+	hp *hostPool
+
+	mu         sync.Mutex
+	maxStreams uint32
+	active     uint32
+	draining   bool
+	bad        bool
+}
+
+func newPooledConn(hp *hostPool, defaultMaxStreams uint32) *pooledConn {
+	return &pooledConn{hp: hp, maxStreams: defaultMaxStreams}
+}
+
+// reserve atomically takes a stream slot if the connection has room,
+// reporting whether it did.
+func (c *pooledConn) reserve() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining || c.bad {
+		return false
+	}
+
+	max := c.maxStreams
+	if hinter, ok := c.ClientStreamConnection.(MaxStreamsHinter); ok {
+		if h := hinter.MaxConcurrentStreams(); h > 0 {
+			max = h
+		}
+	}
+
+	if uint32(c.active) >= max {
+		return false
+	}
+	c.active++
+	return true
+}
+
+func (c *pooledConn) release() {
+	c.mu.Lock()
+	if c.active > 0 {
+		c.active--
+	}
+	c.mu.Unlock()
+}
+
+func (c *pooledConn) markBad() {
+	c.mu.Lock()
+	c.bad = true
+	c.mu.Unlock()
+}
+
+// NewStream reserves nothing itself -- the slot is already reserved by
+// the time GetConn hands this connection out -- but it wraps the
+// decoder and the stream's event listener so the slot is released
+// exactly once, whichever of "stream ended" or "stream reset" happens
+// first.
+func (c *pooledConn) NewStream(streamID string, responseDecoder types.StreamDecoder) types.StreamEncoder {
+	once := &releaseOnce{fn: c.release}
+
+	encoder := c.ClientStreamConnection.NewStream(streamID, &releasingDecoder{
+		StreamDecoder: responseDecoder,
+		once:          once,
+	})
+	encoder.GetStream().AddEventListener(&releasingListener{conn: c, once: once})
+
+	return encoder
+}
+
+// OnGoAway marks the connection draining: it keeps serving the streams
+// already reserved on it, but stops being handed out for new ones.
+func (c *pooledConn) OnGoAway() {
+	c.mu.Lock()
+	c.draining = true
+	c.mu.Unlock()
+}
+
+// releaseOnce runs fn the first time do is called, and is shared
+// between a stream's decoder wrapper and its event listener so a
+// completed-then-reset (or reset-then-completed) stream only releases
+// its slot once.
+type releaseOnce struct {
+	fn   func()
+	done int32
+}
+
+func (r *releaseOnce) do() {
+	if atomic.CompareAndSwapInt32(&r.done, 0, 1) {
+		r.fn()
+	}
+}
+
+type releasingDecoder struct {
+	types.StreamDecoder
+	once *releaseOnce
+}
+
+func (d *releasingDecoder) OnDecodeHeaders(headers map[string]string, endStream bool) {
+	d.StreamDecoder.OnDecodeHeaders(headers, endStream)
+	if endStream {
+		d.once.do()
+	}
+}
+
+func (d *releasingDecoder) OnDecodeData(data types.IoBuffer, endStream bool) {
+	d.StreamDecoder.OnDecodeData(data, endStream)
+	if endStream {
+		d.once.do()
+	}
+}
+
+func (d *releasingDecoder) OnDecodeTrailers(trailers map[string]string) {
+	d.StreamDecoder.OnDecodeTrailers(trailers)
+	d.once.do()
+}
+
+type releasingListener struct {
+	conn *pooledConn
+	once *releaseOnce
+}
+
+func (l *releasingListener) OnResetStream(reason types.StreamResetReason) {
+	l.once.do()
+
+	// StreamConnectionFailed and StreamConnectionTermination both mean
+	// the underlying connection itself is gone, not just this one
+	// stream -- every other stream on it is about to fail (or has
+	// already failed) the same way, so stop handing it out. Every other
+	// reason is a per-stream reset that says nothing about the rest of
+	// the connection's health.
+	switch reason {
+	case types.StreamConnectionFailed, types.StreamConnectionTermination:
+		l.conn.markBad()
+	}
+}
+
+func (l *releasingListener) OnAboveWriteBufferHighWatermark() {}
+
+func (l *releasingListener) OnBelowWriteBufferLowWatermark() {}
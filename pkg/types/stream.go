@@ -1,6 +1,9 @@
 package types
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 //
 //   The bunch of interfaces are structure skeleton to build a extensible protocol stream architecture.
@@ -73,8 +76,68 @@ const (
 	StreamLocalReset            StreamResetReason = "StreamLocalReset"
 	StreamOverflow              StreamResetReason = "StreamOverflow"
 	StreamRemoteReset           StreamResetReason = "StreamRemoteReset"
+	// StreamKeepaliveTimeout is used to reset streams still in flight
+	// when their StreamConnection is torn down by the keepalive
+	// enforcer because MaxConnectionAgeGrace elapsed after a GoAway
+	// without the in-flight streams finishing. A ping that goes unacked
+	// past Timeout is reported as StreamConnectionFailed instead, since
+	// that's a transport failure rather than a graceful age-out.
+	StreamKeepaliveTimeout StreamResetReason = "StreamKeepaliveTimeout"
 )
 
+// KeepaliveParameters configures the keepalive enforcement a
+// StreamConnection that implements Keepaliver applies to its
+// connection. It mirrors gRPC's client/server keepalive knobs so the
+// same policy works whether the StreamConnection is HTTP/2 or
+// bolt/sofa-rpc underneath.
+type KeepaliveParameters struct {
+	// Time is how long the connection stays idle (no read activity)
+	// before a keepalive ping is sent.
+	Time time.Duration
+
+	// Timeout is how long to wait for an ack to an outstanding ping
+	// before the connection is considered dead.
+	Timeout time.Duration
+
+	// MinTime is the minimum allowed interval between two pings
+	// received from a client; a server enforces this.
+	MinTime time.Duration
+
+	// PermitWithoutStream allows pings to be sent/accepted even when
+	// there are no active streams on the connection.
+	PermitWithoutStream bool
+
+	// MaxConnectionIdle is how long a connection may go with no active
+	// streams before the server closes it.
+	MaxConnectionIdle time.Duration
+
+	// MaxConnectionAge is how long a connection may live before the
+	// server sends it a GoAway.
+	MaxConnectionAge time.Duration
+
+	// MaxConnectionAgeGrace is how long the server waits for in-flight
+	// streams to finish after MaxConnectionAge before force-resetting
+	// them with StreamKeepaliveTimeout.
+	MaxConnectionAgeGrace time.Duration
+}
+
+// Keepaliver is implemented by StreamConnection implementations that
+// support transport-agnostic keepalive enforcement (sending/answering
+// pings, idle/age based GoAway). It sits between Connection and
+// StreamConnection: the enforcer owns the timers, the StreamConnection
+// just needs to supply protocol-specific ping/GoAway mechanics.
+type Keepaliver interface {
+	// SendPing sends a protocol-level keepalive ping (HTTP/2 PING, bolt
+	// heartbeat) and returns once it's written to the wire.
+	SendPing() error
+
+	// OnGoAway is called by the enforcer to start a graceful shutdown,
+	// e.g. because MaxConnectionAge was reached or a client exceeded
+	// MinTime between pings. reason is surfaced to the peer where the
+	// underlying protocol supports it (HTTP/2's debug data).
+	OnGoAway(reason string)
+}
+
 // Core model in stream layer, a generic protocol stream
 type Stream interface {
 	// Add stream event listener
@@ -149,6 +212,15 @@ type StreamConnection interface {
 
 	// Called when the underlying Connection goes from over its high watermark to under its low watermark.
 	OnUnderlyingConnectionBelowWriteBufferLowWatermark()
+
+	// UpdateFlowControlWindow raises the read window advertised to the
+	// peer for streamID by delta bytes on the underlying transport (the
+	// HTTP/2 WINDOW_UPDATE frame, or the bolt/sofa-rpc equivalent).
+	// streamID is empty when the update applies to the whole connection
+	// rather than a single stream. Implementations that don't support
+	// per-connection flow control, such as protocols without a concept
+	// of a stream-level window, may treat this as a no-op.
+	UpdateFlowControlWindow(streamID string, delta int64) error
 }
 
 // A server side stream connection.